@@ -0,0 +1,166 @@
+package client
+
+import "fmt"
+import "net/url"
+
+type CustomField struct {
+	GID     string               `json:"gid"`
+	Name    string               `json:"name"`
+	Type    string               `json:"resource_subtype"`
+	Options []*CustomFieldOption `json:"enum_options"`
+}
+
+type CustomFieldOption struct {
+	GID  string `json:"gid"`
+	Name string `json:"name"`
+}
+
+type customFieldSetting struct {
+	CustomField *CustomField `json:"custom_field"`
+}
+
+type customFieldSettingsResponse struct {
+	Data     []*customFieldSetting `json:"data"`
+	NextPage *nextPage             `json:"next_page"`
+}
+
+type customFieldsResponse struct {
+	Data     []*CustomField `json:"data"`
+	NextPage *nextPage      `json:"next_page"`
+}
+
+type taskCustomFieldValue struct {
+	GID             string               `json:"gid"`
+	NumberValue     *float64             `json:"number_value"`
+	TextValue       *string              `json:"text_value"`
+	EnumValue       *CustomFieldOption   `json:"enum_value"`
+	MultiEnumValues []*CustomFieldOption `json:"multi_enum_values"`
+}
+
+type taskCustomFieldsResponse struct {
+	Data struct {
+		CustomFields []*taskCustomFieldValue `json:"custom_fields"`
+	} `json:"data"`
+}
+
+// GetCustomFieldsByName returns project's custom field definitions keyed
+// by name.
+func (wc *WorkspaceClient) GetCustomFieldsByName(project *Project) (map[string]*CustomField, error) {
+	ret := []*CustomField{}
+
+	path := fmt.Sprintf("projects/%s/custom_field_settings", project.GID)
+	values := &url.Values{}
+
+	for {
+		resp := &customFieldSettingsResponse{}
+		err := wc.client.get(path, values, resp)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, setting := range resp.Data {
+			ret = append(ret, setting.CustomField)
+		}
+
+		if resp.NextPage == nil {
+			break
+		}
+
+		values.Set("offset", resp.NextPage.Offset)
+	}
+
+	byName := map[string]*CustomField{}
+	for _, cf := range ret {
+		byName[cf.Name] = cf
+	}
+
+	return byName, nil
+}
+
+// GetWorkspaceCustomFieldsByName returns the workspace's custom field
+// definitions keyed by name. Unlike GetCustomFieldsByName, this isn't
+// scoped to a single project, so it also finds fields query clauses
+// reference that aren't on the caller's My Tasks project.
+func (wc *WorkspaceClient) GetWorkspaceCustomFieldsByName() (map[string]*CustomField, error) {
+	ret := []*CustomField{}
+
+	path := fmt.Sprintf("workspaces/%s/custom_fields", wc.workspaceGID)
+	values := &url.Values{}
+
+	for {
+		resp := &customFieldsResponse{}
+		err := wc.client.get(path, values, resp)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, resp.Data...)
+
+		if resp.NextPage == nil {
+			break
+		}
+
+		values.Set("offset", resp.NextPage.Offset)
+	}
+
+	byName := map[string]*CustomField{}
+	for _, cf := range ret {
+		byName[cf.Name] = cf
+	}
+
+	return byName, nil
+}
+
+// GetTaskCustomFieldValue re-fetches t from the API and returns its current
+// value for the custom field identified by fieldGID: a float64, a string,
+// an option GID (enum), a []string of option GIDs (multi-enum), or nil if
+// the field isn't set on the task. Search results don't carry per-field
+// values, so callers that need to re-check a custom-field clause locally
+// (see rules.WithCustomFieldEquals and friends) go through this instead of
+// reading t directly.
+func (wc *WorkspaceClient) GetTaskCustomFieldValue(t *Task, fieldGID string) (any, error) {
+	resp := &taskCustomFieldsResponse{}
+
+	path := fmt.Sprintf("tasks/%s", t.GID)
+	values := &url.Values{}
+	values.Set("opt_fields", "custom_fields")
+
+	err := wc.client.get(path, values, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cf := range resp.Data.CustomFields {
+		if cf.GID != fieldGID {
+			continue
+		}
+
+		switch {
+		case cf.NumberValue != nil:
+			return *cf.NumberValue, nil
+		case cf.TextValue != nil:
+			return *cf.TextValue, nil
+		case cf.EnumValue != nil:
+			return cf.EnumValue.GID, nil
+		case cf.MultiEnumValues != nil:
+			gids := make([]string, 0, len(cf.MultiEnumValues))
+			for _, opt := range cf.MultiEnumValues {
+				gids = append(gids, opt.GID)
+			}
+			return gids, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// OptionGID resolves an enum or multi-enum option's name to its GID.
+func (cf *CustomField) OptionGID(name string) (string, error) {
+	for _, opt := range cf.Options {
+		if opt.Name == name {
+			return opt.GID, nil
+		}
+	}
+
+	return "", fmt.Errorf("Custom field '%s' option '%s' not found", cf.Name, name)
+}
@@ -0,0 +1,33 @@
+package client
+
+import "cloud.google.com/go/civil"
+
+// SearchQuery describes an Asana task search request. Each field mirrors a
+// parameter recognized by the tasks/search endpoint.
+type SearchQuery struct {
+	AssigneeAny []*User
+	TagsAny     []*Tag
+	TagsNot     []*Tag
+	SectionsAny []*Section
+
+	DueOn     *civil.Date
+	DueAfter  *civil.Date
+	DueBefore *civil.Date
+	Due       *bool
+
+	Completed *bool
+
+	// Custom field clauses, keyed by custom-field GID (see
+	// WorkspaceClient.GetCustomFieldsByName).
+	CustomFieldEq       map[string]any
+	CustomFieldGt       map[string]float64
+	CustomFieldLt       map[string]float64
+	CustomFieldContains map[string][]string
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+var TRUE = boolPtr(true)
+var FALSE = boolPtr(false)
@@ -0,0 +1,139 @@
+package rules
+
+import "encoding/json"
+import "fmt"
+import "net"
+import "net/http"
+
+type ruleSummary struct {
+	ID          string     `json:"id"`
+	Workspace   string     `json:"workspace"`
+	Description string     `json:"description"`
+	Paused      bool       `json:"paused"`
+	LastRun     *RunRecord `json:"last_run,omitempty"`
+}
+
+type ruleDetail struct {
+	ruleSummary
+	History []*RunRecord `json:"history"`
+}
+
+// AdminHandler returns the admin HTTP handler, mountable on any mux:
+//
+//	GET  /rules             list registered rules
+//	GET  /rules/{id}        rule detail, including recent run history
+//	POST /rules/{id}/run    trigger an immediate exec, bypassing gates
+//	POST /rules/{id}/pause  pause the rule's loop
+//	POST /rules/{id}/resume resume the rule's loop
+func AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /rules", handleList)
+	mux.HandleFunc("GET /rules/{id}", handleDetail)
+	mux.HandleFunc("POST /rules/{id}/run", handleRun)
+	mux.HandleFunc("POST /rules/{id}/pause", handlePause)
+	mux.HandleFunc("POST /rules/{id}/resume", handleResume)
+
+	return mux
+}
+
+// ServeAdmin starts the admin HTTP handler listening on addr in the
+// background. Call it before Loop().
+func ServeAdmin(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := http.Serve(ln, AdminHandler()); err != nil {
+			fmt.Printf("ERROR: rules: admin server: %s\n", err)
+		}
+	}()
+
+	return nil
+}
+
+func handleList(w http.ResponseWriter, r *http.Request) {
+	ret := []*ruleSummary{}
+
+	for _, rule := range Rules() {
+		ret = append(ret, summarize(rule))
+	}
+
+	writeJSON(w, ret)
+}
+
+func handleDetail(w http.ResponseWriter, r *http.Request) {
+	rule := lookupAdminRule(w, r)
+	if rule == nil {
+		return
+	}
+
+	writeJSON(w, &ruleDetail{
+		ruleSummary: *summarize(rule),
+		History:     rule.History(),
+	})
+}
+
+func handleRun(w http.ResponseWriter, r *http.Request) {
+	rule := lookupAdminRule(w, r)
+	if rule == nil {
+		return
+	}
+
+	if err := rule.RunNow(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handlePause(w http.ResponseWriter, r *http.Request) {
+	rule := lookupAdminRule(w, r)
+	if rule == nil {
+		return
+	}
+
+	rule.SetPaused(true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleResume(w http.ResponseWriter, r *http.Request) {
+	rule := lookupAdminRule(w, r)
+	if rule == nil {
+		return
+	}
+
+	rule.SetPaused(false)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func lookupAdminRule(w http.ResponseWriter, r *http.Request) Rule {
+	rule := RuleByID(r.PathValue("id"))
+	if rule == nil {
+		http.Error(w, fmt.Sprintf("rule '%s' not found", r.PathValue("id")), http.StatusNotFound)
+		return nil
+	}
+
+	return rule
+}
+
+func summarize(rule Rule) *ruleSummary {
+	return &ruleSummary{
+		ID:          rule.ID(),
+		Workspace:   rule.Workspace(),
+		Description: rule.Describe(),
+		Paused:      rule.Paused(),
+		LastRun:     rule.LastRun(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
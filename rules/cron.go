@@ -0,0 +1,208 @@
+package rules
+
+import "fmt"
+import "strconv"
+import "strings"
+import "time"
+
+// cronSchedule is a parsed cron expression: second (optional), minute, hour,
+// day of month, month, day of week. Each field is a bitmask of the values
+// that satisfy it.
+type cronSchedule struct {
+	seconds uint64 // bits 0-59; bit 0 set (second 0) when no seconds field was given
+	hasSecs bool
+	minutes uint64 // bits 0-59
+	hours   uint32 // bits 0-23
+	dom     uint32 // bits 1-31
+	domAny  bool   // day-of-month field was "*" (unrestricted)
+	months  uint16 // bits 1-12
+	dow     uint8  // bits 0-6, Sunday == 0
+	dowAny  bool   // day-of-week field was "*" (unrestricted)
+}
+
+var dowNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+// parseCron accepts standard 5-field (minute hour dom month dow) or
+// 6-field (second minute hour dom month dow) cron expressions.
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+
+	var secField string
+	var minField, hourField, domField, monthField, dowField string
+
+	switch len(fields) {
+	case 5:
+		minField, hourField, domField, monthField, dowField = fields[0], fields[1], fields[2], fields[3], fields[4]
+	case 6:
+		secField, minField, hourField, domField, monthField, dowField = fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+	default:
+		return nil, fmt.Errorf("cron: expected 5 or 6 fields, got %d: %q", len(fields), spec)
+	}
+
+	ret := &cronSchedule{}
+
+	if secField != "" {
+		secs, err := parseCronField(secField, 0, 59, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cron: seconds: %w", err)
+		}
+		ret.seconds = secs
+		ret.hasSecs = true
+	} else {
+		ret.seconds = 1 // second 0 only
+	}
+
+	mins, err := parseCronField(minField, 0, 59, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cron: minute: %w", err)
+	}
+	ret.minutes = mins
+
+	hours, err := parseCronField(hourField, 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cron: hour: %w", err)
+	}
+	ret.hours = uint32(hours)
+
+	dom, err := parseCronField(domField, 1, 31, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cron: day of month: %w", err)
+	}
+	ret.dom = uint32(dom)
+	ret.domAny = domField == "*"
+
+	months, err := parseCronField(monthField, 1, 12, monthNames)
+	if err != nil {
+		return nil, fmt.Errorf("cron: month: %w", err)
+	}
+	ret.months = uint16(months)
+
+	// Accept both 0 and 7 for Sunday, per standard cron; fold bit 7 into
+	// bit 0 so matches only ever has to test bits 0-6.
+	dow, err := parseCronField(dowField, 0, 7, dowNames)
+	if err != nil {
+		return nil, fmt.Errorf("cron: day of week: %w", err)
+	}
+	if dow&(1<<7) != 0 {
+		dow |= 1 << 0
+		dow &^= 1 << 7
+	}
+	ret.dow = uint8(dow)
+	ret.dowAny = dowField == "*"
+
+	return ret, nil
+}
+
+// parseCronField parses a single comma-separated cron field (supporting
+// ranges, steps and names) into a bitmask with bit N set when value N
+// satisfies the field.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart := part
+		step := 1
+
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:idx]
+		}
+
+		lo, hi := min, max
+
+		if rangePart != "*" {
+			if idx := strings.Index(rangePart, "-"); idx >= 0 {
+				var err error
+				lo, err = parseCronValue(rangePart[:idx], names)
+				if err != nil {
+					return 0, err
+				}
+				hi, err = parseCronValue(rangePart[idx+1:], names)
+				if err != nil {
+					return 0, err
+				}
+			} else {
+				v, err := parseCronValue(rangePart, names)
+				if err != nil {
+					return 0, err
+				}
+				lo, hi = v, v
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range in %q (expected %d-%d)", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+
+	return mask, nil
+}
+
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if names != nil {
+		if v, found := names[strings.ToUpper(s)]; found {
+			return v, nil
+		}
+	}
+
+	return strconv.Atoi(s)
+}
+
+// nextFire returns the next time strictly after `after` that satisfies the
+// schedule, at second granularity.
+func (cs *cronSchedule) nextFire(after time.Time) time.Time {
+	t := after.Truncate(time.Second).Add(time.Second)
+
+	for i := 0; i < 4*366*24*60*60; i++ {
+		if cs.matches(t) {
+			return t
+		}
+
+		if !cs.hasSecs {
+			// Without a seconds field, skip straight to the next minute.
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Add(time.Minute)
+		} else {
+			t = t.Add(time.Second)
+		}
+	}
+
+	// Should be unreachable for any valid schedule.
+	return t
+}
+
+func (cs *cronSchedule) matches(t time.Time) bool {
+	domMatch := cs.dom&(1<<uint(t.Day())) != 0
+	dowMatch := cs.dow&(1<<uint(t.Weekday())) != 0
+
+	// Standard cron ORs day-of-month and day-of-week when both are
+	// restricted (e.g. "0 0 13 * FRI" fires on the 13th or any Friday);
+	// when at most one is restricted this is equivalent to ANDing them,
+	// since the unrestricted field's bitmask always matches.
+	var domDowMatch bool
+	if !cs.domAny && !cs.dowAny {
+		domDowMatch = domMatch || dowMatch
+	} else {
+		domDowMatch = domMatch && dowMatch
+	}
+
+	return cs.seconds&(1<<uint(t.Second())) != 0 &&
+		cs.minutes&(1<<uint(t.Minute())) != 0 &&
+		cs.hours&(1<<uint(t.Hour())) != 0 &&
+		domDowMatch &&
+		cs.months&(1<<uint(t.Month())) != 0
+}
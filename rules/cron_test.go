@@ -0,0 +1,118 @@
+package rules
+
+import "testing"
+import "time"
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "5 fields", spec: "*/15 9-17 * * MON-FRI"},
+		{name: "6 fields with seconds", spec: "30 */15 9-17 * * MON-FRI"},
+		{name: "dow names list", spec: "0 0 * * SUN,WED,FRI"},
+		{name: "dow 7 means Sunday", spec: "0 0 * * 7"},
+		{name: "month names", spec: "0 0 1 JAN,JUL *"},
+		{name: "too few fields", spec: "* * *", wantErr: true},
+		{name: "too many fields", spec: "* * * * * * *", wantErr: true},
+		{name: "invalid step", spec: "*/0 * * * *", wantErr: true},
+		{name: "out of range hour", spec: "0 24 * * *", wantErr: true},
+		{name: "inverted range", spec: "0 17-9 * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCron(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCron(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleMatchesDowSeven(t *testing.T) {
+	cs, err := parseCron("0 0 * * 7")
+	if err != nil {
+		t.Fatalf("parseCron: %v", err)
+	}
+
+	// 2026-08-02 is a Sunday.
+	sunday := time.Date(2026, time.August, 2, 0, 0, 0, 0, time.UTC)
+	if !cs.matches(sunday) {
+		t.Errorf("expected dow=7 to match Sunday")
+	}
+}
+
+func TestCronScheduleOrsDomAndDowWhenBothRestricted(t *testing.T) {
+	cs, err := parseCron("0 0 13 * FRI")
+	if err != nil {
+		t.Fatalf("parseCron: %v", err)
+	}
+
+	// 2026-01-13 is a Tuesday: matches via dom only.
+	thirteenthNotFriday := time.Date(2026, time.January, 13, 0, 0, 0, 0, time.UTC)
+	if !cs.matches(thirteenthNotFriday) {
+		t.Errorf("expected the 13th (non-Friday) to match via OR")
+	}
+
+	// 2026-01-16 is a Friday: matches via dow only.
+	fridayNot13th := time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC)
+	if !cs.matches(fridayNot13th) {
+		t.Errorf("expected a Friday (non-13th) to match via OR")
+	}
+
+	// 2026-01-14 is neither.
+	neither := time.Date(2026, time.January, 14, 0, 0, 0, 0, time.UTC)
+	if cs.matches(neither) {
+		t.Errorf("expected a day that's neither the 13th nor a Friday to not match")
+	}
+}
+
+func TestCronScheduleAndsDomAndDowWhenOnlyOneRestricted(t *testing.T) {
+	// dow is "*" (unrestricted), so this reduces to dom-only matching.
+	cs, err := parseCron("0 0 13 * *")
+	if err != nil {
+		t.Fatalf("parseCron: %v", err)
+	}
+
+	thirteenth := time.Date(2026, time.January, 13, 0, 0, 0, 0, time.UTC)
+	if !cs.matches(thirteenth) {
+		t.Errorf("expected the 13th to match")
+	}
+
+	fourteenth := time.Date(2026, time.January, 14, 0, 0, 0, 0, time.UTC)
+	if cs.matches(fourteenth) {
+		t.Errorf("expected a non-13th day to not match when dow is unrestricted")
+	}
+}
+
+func TestNextFireWithNoSecondsFieldSkipsToNextMinute(t *testing.T) {
+	cs, err := parseCron("*/15 * * * *")
+	if err != nil {
+		t.Fatalf("parseCron: %v", err)
+	}
+
+	after := time.Date(2026, time.January, 1, 0, 0, 30, 0, time.UTC)
+	got := cs.nextFire(after)
+
+	want := time.Date(2026, time.January, 1, 0, 15, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("nextFire(%v) = %v, want %v", after, got, want)
+	}
+}
+
+func TestNextFireWithSecondsField(t *testing.T) {
+	cs, err := parseCron("30 * * * * *")
+	if err != nil {
+		t.Fatalf("parseCron: %v", err)
+	}
+
+	after := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	got := cs.nextFire(after)
+
+	want := time.Date(2026, time.January, 1, 0, 0, 30, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("nextFire(%v) = %v, want %v", after, got, want)
+	}
+}
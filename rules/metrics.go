@@ -0,0 +1,38 @@
+package rules
+
+import "net/http"
+
+import "github.com/prometheus/client_golang/prometheus"
+import "github.com/prometheus/client_golang/prometheus/promauto"
+import "github.com/prometheus/client_golang/prometheus/promhttp"
+
+var ruleExecutionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automana_rule_executions_total",
+	Help: "Total number of rule exec invocations, by result (ok/error).",
+}, []string{"rule", "result"})
+
+var ruleDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "automana_rule_duration_seconds",
+	Help: "Duration of each exec phase (gate/query/filter/actor), in seconds.",
+}, []string{"rule", "phase"})
+
+var tasksMatchedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automana_tasks_matched_total",
+	Help: "Total number of tasks matched by a rule's search query.",
+}, []string{"rule"})
+
+var tasksActedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automana_tasks_acted_total",
+	Help: "Total number of tasks successfully acted on, by actor.",
+}, []string{"rule", "actor"})
+
+var asanaAPIErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automana_asana_api_errors_total",
+	Help: "Total number of errors surfaced from Asana API calls, by endpoint.",
+}, []string{"endpoint"})
+
+// MetricsHandler returns the standard Prometheus scrape handler, mountable
+// at /metrics on any mux.
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
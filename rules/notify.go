@@ -0,0 +1,300 @@
+package rules
+
+import "bytes"
+import "context"
+import "encoding/json"
+import "fmt"
+import "io"
+import "net"
+import "net/http"
+import "net/smtp"
+import "strings"
+import "sync"
+import "text/template"
+import "time"
+
+import "github.com/firestuff/automana/client"
+import bolt "go.etcd.io/bbolt"
+
+// defaultNotificationCooldown is the dedup window used when a rule hasn't
+// called WithNotificationCooldown.
+const defaultNotificationCooldown = 24 * time.Hour
+
+const notificationBucket = "notifications"
+
+var notificationStoreMu sync.Mutex
+var notificationStore *bolt.DB
+
+// EmailConfig holds the SMTP connection and envelope details used by
+// NotifyEmail.
+type EmailConfig struct {
+	SMTPAddr string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// SetNotificationStore opens (creating if necessary) a bolt database at
+// path, used by the Notify* actors to deduplicate notifications for the
+// same (rule, task) pair within their cooldown window. Call before Loop.
+// If never called, the Notify* actors send unconditionally.
+func SetNotificationStore(path string) error {
+	notificationStoreMu.Lock()
+	defer notificationStoreMu.Unlock()
+
+	if notificationStore != nil {
+		notificationStore.Close()
+	}
+
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(notificationBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	notificationStore = db
+
+	return nil
+}
+
+// notificationKey identifies one (rule, actor, destination, task) tuple in
+// the notification store. dest distinguishes multiple Notify* actors on
+// the same rule (e.g. NotifySlack and NotifyEmail both acting on the same
+// task) so they don't collide on a single shared key.
+func notificationKey(ruleID, actor, dest, taskGID string) []byte {
+	return []byte(strings.Join([]string{ruleID, actor, dest, taskGID}, "\x00"))
+}
+
+// notificationSent reports whether key has an unexpired record of a
+// previously-sent notification, i.e. whether a new send should be
+// suppressed. With no store configured, it always returns false.
+func notificationSent(key []byte) (bool, error) {
+	notificationStoreMu.Lock()
+	db := notificationStore
+	notificationStoreMu.Unlock()
+
+	if db == nil {
+		return false, nil
+	}
+
+	sent := false
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(notificationBucket))
+
+		v := b.Get(key)
+		if v == nil {
+			return nil
+		}
+
+		expiry, err := time.Parse(time.RFC3339, string(v))
+		if err == nil && time.Now().Before(expiry) {
+			sent = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return sent, nil
+}
+
+// recordNotificationSent marks key as notified for the rule's cooldown
+// window (see WithNotificationCooldown). Callers must only call this after
+// the send itself has succeeded, so a failed send isn't suppressed for the
+// rest of the cooldown window. With no store configured, it's a no-op.
+func (p *periodic) recordNotificationSent(key []byte) error {
+	notificationStoreMu.Lock()
+	db := notificationStore
+	notificationStoreMu.Unlock()
+
+	if db == nil {
+		return nil
+	}
+
+	cooldown := p.cooldown
+	if cooldown <= 0 {
+		cooldown = defaultNotificationCooldown
+	}
+
+	expiry := time.Now().Add(cooldown).Format(time.RFC3339)
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(notificationBucket))
+		return b.Put(key, []byte(expiry))
+	})
+}
+
+// renderNotificationTemplate parses and executes a text/template string
+// against t, giving the template access to the task's Name, GID,
+// PermalinkURL, Assignee, DueOn and Notes.
+func renderNotificationTemplate(name, tmplText string, t *client.Task) (string, error) {
+	tmpl, err := template.New(name).Parse(tmplText)
+	if err != nil {
+		return "", fmt.Errorf("notify: parsing %s template: %w", name, err)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := tmpl.Execute(buf, t); err != nil {
+		return "", fmt.Errorf("notify: rendering %s template: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// NotifySlack posts template, rendered against the matched task, as a
+// Slack message via an incoming webhook. Sends are deduplicated per
+// rule, webhook URL and task; see SetNotificationStore and
+// WithNotificationCooldown.
+func (p *periodic) NotifySlack(webhookURL, tmpl string) *periodic {
+	p.taskActors = append(p.taskActors, func(ctx context.Context, wc *client.WorkspaceClient, t *client.Task) error {
+		key := notificationKey(p.id, "NotifySlack", webhookURL, t.GID)
+
+		sent, err := notificationSent(key)
+		if err != nil {
+			return err
+		}
+
+		if sent {
+			return nil
+		}
+
+		text, err := renderNotificationTemplate("slack", tmpl, t)
+		if err != nil {
+			return err
+		}
+
+		body, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return err
+		}
+
+		headers := map[string]string{"Content-Type": "application/json"}
+
+		if err := postNotification(ctx, http.MethodPost, webhookURL, headers, body); err != nil {
+			return err
+		}
+
+		return p.recordNotificationSent(key)
+	})
+	p.taskActorNames = append(p.taskActorNames, "NotifySlack")
+
+	return p
+}
+
+// NotifyEmail renders subjectTmpl and bodyTmpl against the matched task and
+// sends the result via cfg's SMTP server. Sends are deduplicated per
+// rule, SMTP server and recipient list, and task; see SetNotificationStore
+// and WithNotificationCooldown.
+func (p *periodic) NotifyEmail(cfg EmailConfig, subjectTmpl, bodyTmpl string) *periodic {
+	p.taskActors = append(p.taskActors, func(ctx context.Context, wc *client.WorkspaceClient, t *client.Task) error {
+		dest := cfg.SMTPAddr + ">" + strings.Join(cfg.To, ",")
+		key := notificationKey(p.id, "NotifyEmail", dest, t.GID)
+
+		sent, err := notificationSent(key)
+		if err != nil {
+			return err
+		}
+
+		if sent {
+			return nil
+		}
+
+		subject, err := renderNotificationTemplate("email_subject", subjectTmpl, t)
+		if err != nil {
+			return err
+		}
+
+		body, err := renderNotificationTemplate("email_body", bodyTmpl, t)
+		if err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", cfg.From, strings.Join(cfg.To, ", "), subject, body)
+
+		host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+		if err != nil {
+			return fmt.Errorf("notify: %w", err)
+		}
+
+		auth := smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+
+		if err := smtp.SendMail(cfg.SMTPAddr, auth, cfg.From, cfg.To, []byte(msg)); err != nil {
+			return err
+		}
+
+		return p.recordNotificationSent(key)
+	})
+	p.taskActorNames = append(p.taskActorNames, "NotifyEmail")
+
+	return p
+}
+
+// NotifyWebhook renders bodyTmpl against the matched task and sends it as
+// the body of a method request to url, with headers set verbatim. Sends
+// are deduplicated per rule, method and url, and task; see
+// SetNotificationStore and WithNotificationCooldown.
+func (p *periodic) NotifyWebhook(url string, method string, headers map[string]string, bodyTmpl string) *periodic {
+	p.taskActors = append(p.taskActors, func(ctx context.Context, wc *client.WorkspaceClient, t *client.Task) error {
+		key := notificationKey(p.id, "NotifyWebhook", method+" "+url, t.GID)
+
+		sent, err := notificationSent(key)
+		if err != nil {
+			return err
+		}
+
+		if sent {
+			return nil
+		}
+
+		body, err := renderNotificationTemplate("webhook", bodyTmpl, t)
+		if err != nil {
+			return err
+		}
+
+		if err := postNotification(ctx, method, url, headers, []byte(body)); err != nil {
+			return err
+		}
+
+		return p.recordNotificationSent(key)
+	})
+	p.taskActorNames = append(p.taskActorNames, "NotifyWebhook")
+
+	return p
+}
+
+func postNotification(ctx context.Context, method, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("notify: %s %s returned %s: %s", method, url, resp.Status, b)
+	}
+
+	return nil
+}
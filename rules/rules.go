@@ -1,8 +1,16 @@
 package rules
 
 import "bytes"
+import "context"
+import "errors"
 import "fmt"
+import "log/slog"
+import "math/rand"
+import "os"
+import "os/signal"
 import "strings"
+import "sync"
+import "syscall"
 import "time"
 
 import "cloud.google.com/go/civil"
@@ -10,22 +18,56 @@ import "github.com/firestuff/automana/client"
 import "golang.org/x/net/html"
 import "golang.org/x/net/html/atom"
 
-type workspaceClientGetter func(*client.Client) (*client.WorkspaceClient, error)
-type gate func(*client.WorkspaceClient) (bool, error)
-type queryMutator func(*client.WorkspaceClient, *client.SearchQuery) error
-type taskActor func(*client.WorkspaceClient, *client.Task) error
-type taskFilter func(*client.WorkspaceClient, *client.SearchQuery, *client.Task) (bool, error)
+type workspaceClientGetter func(context.Context, *client.Client) (*client.WorkspaceClient, error)
+type gate func(context.Context, *client.WorkspaceClient) (bool, error)
+type queryMutator func(context.Context, *client.WorkspaceClient, *client.SearchQuery) error
+type taskActor func(context.Context, *client.WorkspaceClient, *client.Task) error
+type taskFilter func(context.Context, *client.WorkspaceClient, *client.SearchQuery, *client.Task) (bool, error)
 
 type periodic struct {
 	done chan bool
 
+	id        string
+	workspace string
+	client    *client.Client
+
 	workspaceClientGetter workspaceClientGetter
 	gates                 []gate
 	queryMutators         []queryMutator
 	taskFilters           []taskFilter
 	taskActors            []taskActor
+	taskActorNames        []string
+
+	interval    time.Duration
+	cron        *cronSchedule
+	cronErr     error
+	jitter      time.Duration
+	timeout     time.Duration
+	concurrency int
+	cooldown    time.Duration
+
+	mu      sync.Mutex
+	paused  bool
+	history []*RunRecord
 }
 
+// ruleHistorySize is the number of past executions retained per rule for
+// admin inspection (see rulesweb).
+const ruleHistorySize = 20
+
+// RunRecord is a snapshot of a single exec invocation.
+type RunRecord struct {
+	At       time.Time
+	Matched  int
+	Filtered int
+	Error    string
+}
+
+// Rule is the external handle on a registered rule, returned by Rules and
+// RuleByID for inspection and control by admin tooling (see the rulesweb
+// package).
+type Rule = *periodic
+
 type Weekday = time.Weekday
 
 const (
@@ -53,11 +95,36 @@ var WeekendDays = []Weekday{
 
 var periodics = []*periodic{}
 
-func Loop() {
+var (
+	rootCancel context.CancelFunc
+)
+
+var logger = slog.Default()
+
+// SetLogger replaces the package's logger. Records include rule_id,
+// workspace, phase (gate/query/filter/actor), task_gid and error fields
+// where applicable.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
+// Loop starts every registered rule and blocks until they have all stopped.
+// SIGINT and SIGTERM trigger an automatic Shutdown.
+func Loop(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	rootCancel = cancel
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
 	c := client.NewClientFromEnv()
 
 	for _, periodic := range periodics {
-		periodic.start(c)
+		periodic.start(ctx, c)
 	}
 
 	for _, periodic := range periodics {
@@ -65,10 +132,37 @@ func Loop() {
 	}
 }
 
+// Shutdown cancels the root context passed to Loop, then waits for every
+// rule's in-flight exec (if any) to finish, up to ctx's deadline.
+func Shutdown(ctx context.Context) error {
+	if rootCancel == nil {
+		return nil
+	}
+
+	rootCancel()
+
+	done := make(chan struct{})
+	go func() {
+		for _, periodic := range periodics {
+			periodic.wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func InWorkspace(name string) *periodic {
 	ret := &periodic{
-		done: make(chan bool),
-		workspaceClientGetter: func(c *client.Client) (*client.WorkspaceClient, error) {
+		done:      make(chan bool),
+		id:        fmt.Sprintf("%d", len(periodics)),
+		workspace: name,
+		workspaceClientGetter: func(ctx context.Context, c *client.Client) (*client.WorkspaceClient, error) {
 			return c.InWorkspace(name)
 		},
 	}
@@ -78,9 +172,26 @@ func InWorkspace(name string) *periodic {
 	return ret
 }
 
+// Rules returns every registered rule, for inspection by admin tooling.
+func Rules() []Rule {
+	return periodics
+}
+
+// RuleByID returns the registered rule with the given ID, or nil if none
+// matches.
+func RuleByID(id string) Rule {
+	for _, p := range periodics {
+		if p.id == id {
+			return p
+		}
+	}
+
+	return nil
+}
+
 // Gates
 func (p *periodic) WhenBetween(tz, start, end string) *periodic {
-	p.gates = append(p.gates, func(wc *client.WorkspaceClient) (bool, error) {
+	p.gates = append(p.gates, func(ctx context.Context, wc *client.WorkspaceClient) (bool, error) {
 		loc, err := time.LoadLocation(tz)
 		if err != nil {
 			return false, err
@@ -110,7 +221,7 @@ func (p *periodic) WhenBetween(tz, start, end string) *periodic {
 }
 
 func (p *periodic) WhenDayOfWeek(tz string, days []Weekday) *periodic {
-	p.gates = append(p.gates, func(wc *client.WorkspaceClient) (bool, error) {
+	p.gates = append(p.gates, func(ctx context.Context, wc *client.WorkspaceClient) (bool, error) {
 		loc, err := time.LoadLocation(tz)
 		if err != nil {
 			return false, err
@@ -130,9 +241,56 @@ func (p *periodic) WhenDayOfWeek(tz string, days []Weekday) *periodic {
 	return p
 }
 
+// Scheduling
+func (p *periodic) WithInterval(d time.Duration) *periodic {
+	p.interval = d
+	return p
+}
+
+// WithCron schedules execution using a standard 5-field (minute hour dom
+// month dow) or 6-field (second minute hour dom month dow) cron expression,
+// e.g. "*/15 9-17 * * MON-FRI".
+func (p *periodic) WithCron(spec string) *periodic {
+	p.cron, p.cronErr = parseCron(spec)
+	return p
+}
+
+// WithJitter adds a uniformly-random delay in [0, max) before each fire, to
+// spread load across concurrently-registered rules.
+func (p *periodic) WithJitter(max time.Duration) *periodic {
+	p.jitter = max
+	return p
+}
+
+// WithTimeout bounds a single exec invocation; if it stalls past d, it's
+// cancelled and retried on the next tick rather than blocking the loop
+// forever.
+func (p *periodic) WithTimeout(d time.Duration) *periodic {
+	p.timeout = d
+	return p
+}
+
+// WithConcurrency runs the taskActors for up to n matched tasks in
+// parallel. All actors for a single task still run in order on the same
+// worker; only different tasks are processed concurrently. Defaults to 1
+// (serial) when unset.
+func (p *periodic) WithConcurrency(n int) *periodic {
+	p.concurrency = n
+	return p
+}
+
+// WithNotificationCooldown tunes how long the Notify* actors suppress a
+// repeat notification for the same task after one is sent (see
+// SetNotificationStore). Defaults to defaultNotificationCooldown when
+// unset.
+func (p *periodic) WithNotificationCooldown(d time.Duration) *periodic {
+	p.cooldown = d
+	return p
+}
+
 // Query mutators
 func (p *periodic) InMyTasksSections(names ...string) *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		u, err := wc.GetMe()
 		if err != nil {
 			return err
@@ -164,7 +322,7 @@ func (p *periodic) InMyTasksSections(names ...string) *periodic {
 
 	// Backup filter if the API misbehaves
 	// Asana issue #600801
-	p.taskFilters = append(p.taskFilters, func(wc *client.WorkspaceClient, q *client.SearchQuery, t *client.Task) (bool, error) {
+	p.taskFilters = append(p.taskFilters, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery, t *client.Task) (bool, error) {
 		if t.AssigneeSection == nil {
 			return false, fmt.Errorf("missing assignee: %s", t)
 		}
@@ -182,7 +340,7 @@ func (p *periodic) InMyTasksSections(names ...string) *periodic {
 }
 
 func (p *periodic) DueInDays(days int) *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if q.DueOn != nil {
 			return fmt.Errorf("Multiple clauses set DueOn")
 		}
@@ -197,7 +355,7 @@ func (p *periodic) DueInDays(days int) *periodic {
 }
 
 func (p *periodic) DueInAtLeastDays(days int) *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if q.DueAfter != nil {
 			return fmt.Errorf("Multiple clauses set DueAfter")
 		}
@@ -212,7 +370,7 @@ func (p *periodic) DueInAtLeastDays(days int) *periodic {
 }
 
 func (p *periodic) DueInAtMostDays(days int) *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if q.DueBefore != nil {
 			return fmt.Errorf("Multiple clauses set DueBefore")
 		}
@@ -227,7 +385,7 @@ func (p *periodic) DueInAtMostDays(days int) *periodic {
 }
 
 func (p *periodic) OnlyIncomplete() *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if q.Completed != nil {
 			return fmt.Errorf("Multiple clauses set Completed")
 		}
@@ -240,7 +398,7 @@ func (p *periodic) OnlyIncomplete() *periodic {
 }
 
 func (p *periodic) OnlyComplete() *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if q.Completed != nil {
 			return fmt.Errorf("Multiple clauses set Completed")
 		}
@@ -253,7 +411,7 @@ func (p *periodic) OnlyComplete() *periodic {
 }
 
 func (p *periodic) WithTagsAnyOf(names ...string) *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if len(q.TagsAny) > 0 {
 			return fmt.Errorf("Multiple clauses set TagsAny")
 		}
@@ -279,7 +437,7 @@ func (p *periodic) WithTagsAnyOf(names ...string) *periodic {
 }
 
 func (p *periodic) WithoutTagsAnyOf(names ...string) *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if len(q.TagsNot) > 0 {
 			return fmt.Errorf("Multiple clauses set TagsNot")
 		}
@@ -304,9 +462,169 @@ func (p *periodic) WithoutTagsAnyOf(names ...string) *periodic {
 	return p
 }
 
+// Custom field query mutators
+//
+// Custom field names are resolved workspace-wide (see
+// resolveCustomField), since the search query isn't scoped to one
+// project.
+//
+// Each of these also registers a taskFilter fallback, mirroring
+// InMyTasksSections: the filter independently re-resolves the field and
+// re-coerces the target value, rather than reading state left behind by
+// the query mutator, since loop() and an admin-triggered RunNow can exec
+// the same rule concurrently. It then re-fetches the matched task's live
+// custom field value via WorkspaceClient.GetTaskCustomFieldValue to
+// re-check the clause locally if the search API ignored the parameter.
+func (p *periodic) WithCustomFieldEquals(name string, value any) *periodic {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
+		cf, err := resolveCustomField(wc, name)
+		if err != nil {
+			return err
+		}
+
+		v, err := coerceCustomFieldValue(cf, value)
+		if err != nil {
+			return err
+		}
+
+		if q.CustomFieldEq == nil {
+			q.CustomFieldEq = map[string]any{}
+		}
+
+		q.CustomFieldEq[cf.GID] = v
+
+		return nil
+	})
+
+	// Backup filter if the API misbehaves
+	p.taskFilters = append(p.taskFilters, func(ctx context.Context, wc *client.WorkspaceClient, _ *client.SearchQuery, t *client.Task) (bool, error) {
+		cf, err := resolveCustomField(wc, name)
+		if err != nil {
+			return false, err
+		}
+
+		want, err := coerceCustomFieldValue(cf, value)
+		if err != nil {
+			return false, err
+		}
+
+		got, err := wc.GetTaskCustomFieldValue(t, cf.GID)
+		if err != nil {
+			return false, err
+		}
+
+		return got == want, nil
+	})
+
+	return p
+}
+
+func (p *periodic) WithCustomFieldGreaterThan(name string, n float64) *periodic {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
+		cf, err := resolveCustomField(wc, name)
+		if err != nil {
+			return err
+		}
+
+		if q.CustomFieldGt == nil {
+			q.CustomFieldGt = map[string]float64{}
+		}
+
+		q.CustomFieldGt[cf.GID] = n
+
+		return nil
+	})
+
+	// Backup filter if the API misbehaves
+	p.taskFilters = append(p.taskFilters, func(ctx context.Context, wc *client.WorkspaceClient, _ *client.SearchQuery, t *client.Task) (bool, error) {
+		cf, err := resolveCustomField(wc, name)
+		if err != nil {
+			return false, err
+		}
+
+		got, err := wc.GetTaskCustomFieldValue(t, cf.GID)
+		if err != nil {
+			return false, err
+		}
+
+		f, ok := got.(float64)
+		if !ok {
+			return false, nil
+		}
+
+		return f > n, nil
+	})
+
+	return p
+}
+
+// WithCustomFieldOneOf matches tasks whose enum or multi-enum custom field
+// holds any of the named options.
+func (p *periodic) WithCustomFieldOneOf(name string, values ...string) *periodic {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
+		cf, err := resolveCustomField(wc, name)
+		if err != nil {
+			return err
+		}
+
+		gids, err := customFieldOptionGIDs(cf, values)
+		if err != nil {
+			return err
+		}
+
+		if q.CustomFieldContains == nil {
+			q.CustomFieldContains = map[string][]string{}
+		}
+
+		q.CustomFieldContains[cf.GID] = gids
+
+		return nil
+	})
+
+	// Backup filter if the API misbehaves
+	p.taskFilters = append(p.taskFilters, func(ctx context.Context, wc *client.WorkspaceClient, _ *client.SearchQuery, t *client.Task) (bool, error) {
+		cf, err := resolveCustomField(wc, name)
+		if err != nil {
+			return false, err
+		}
+
+		wantGIDs, err := customFieldOptionGIDs(cf, values)
+		if err != nil {
+			return false, err
+		}
+
+		got, err := wc.GetTaskCustomFieldValue(t, cf.GID)
+		if err != nil {
+			return false, err
+		}
+
+		var gotGIDs []string
+		switch v := got.(type) {
+		case string:
+			gotGIDs = []string{v}
+		case []string:
+			gotGIDs = v
+		default:
+			return false, nil
+		}
+
+		for _, g := range gotGIDs {
+			for _, want := range wantGIDs {
+				if g == want {
+					return true, nil
+				}
+			}
+		}
+
+		return false, nil
+	})
+
+	return p
+}
+
 // Task filters
 func (p *periodic) WithUnlinkedURL() *periodic {
-	p.taskFilters = append(p.taskFilters, func(wc *client.WorkspaceClient, _ *client.SearchQuery, t *client.Task) (bool, error) {
+	p.taskFilters = append(p.taskFilters, func(ctx context.Context, wc *client.WorkspaceClient, _ *client.SearchQuery, t *client.Task) (bool, error) {
 		return hasUnlinkedURL(t.ParsedHTMLNotes), nil
 	})
 
@@ -314,7 +632,7 @@ func (p *periodic) WithUnlinkedURL() *periodic {
 }
 
 func (p *periodic) WithoutDue() *periodic {
-	p.queryMutators = append(p.queryMutators, func(wc *client.WorkspaceClient, q *client.SearchQuery) error {
+	p.queryMutators = append(p.queryMutators, func(ctx context.Context, wc *client.WorkspaceClient, q *client.SearchQuery) error {
 		if q.Due != nil {
 			return fmt.Errorf("Multiple clauses set Due")
 		}
@@ -330,7 +648,7 @@ func (p *periodic) WithoutDue() *periodic {
 
 // Task actors
 func (p *periodic) FixUnlinkedURL() *periodic {
-	p.taskActors = append(p.taskActors, func(wc *client.WorkspaceClient, t *client.Task) error {
+	p.taskActors = append(p.taskActors, func(ctx context.Context, wc *client.WorkspaceClient, t *client.Task) error {
 		fixUnlinkedURL(t.ParsedHTMLNotes)
 
 		buf := &bytes.Buffer{}
@@ -349,12 +667,13 @@ func (p *periodic) FixUnlinkedURL() *periodic {
 
 		return wc.UpdateTask(update)
 	})
+	p.taskActorNames = append(p.taskActorNames, "FixUnlinkedURL")
 
 	return p
 }
 
 func (p *periodic) MoveToMyTasksSection(name string) *periodic {
-	p.taskActors = append(p.taskActors, func(wc *client.WorkspaceClient, t *client.Task) error {
+	p.taskActors = append(p.taskActors, func(ctx context.Context, wc *client.WorkspaceClient, t *client.Task) error {
 		utl, err := wc.GetMyUserTaskList()
 		if err != nil {
 			return err
@@ -367,87 +686,343 @@ func (p *periodic) MoveToMyTasksSection(name string) *periodic {
 
 		return wc.AddTaskToSection(t, sec)
 	})
+	p.taskActorNames = append(p.taskActorNames, "MoveToMyTasksSection")
 
 	return p
 }
 
 func (p *periodic) PrintTasks() *periodic {
-	p.taskActors = append(p.taskActors, func(wc *client.WorkspaceClient, t *client.Task) error {
-		fmt.Printf("%s\n", t)
+	p.taskActors = append(p.taskActors, func(ctx context.Context, wc *client.WorkspaceClient, t *client.Task) error {
+		logger.Info("task",
+			"rule_id", p.id,
+			"workspace", p.workspace,
+			"phase", "actor",
+			"task_gid", t.GID,
+		)
 		return nil
 	})
+	p.taskActorNames = append(p.taskActorNames, "PrintTasks")
+
+	return p
+}
+
+// SetCustomField PATCHes the matched task's named custom field. value
+// should already be the right shape for the field's type (a number, a
+// string, or an enum/multi-enum option name).
+func (p *periodic) SetCustomField(name string, value any) *periodic {
+	p.taskActors = append(p.taskActors, func(ctx context.Context, wc *client.WorkspaceClient, t *client.Task) error {
+		cf, err := resolveCustomField(wc, name)
+		if err != nil {
+			return err
+		}
+
+		v, err := coerceCustomFieldValue(cf, value)
+		if err != nil {
+			return err
+		}
+
+		update := &client.Task{
+			GID:          t.GID,
+			CustomFields: map[string]any{cf.GID: v},
+		}
+
+		return wc.UpdateTask(update)
+	})
+	p.taskActorNames = append(p.taskActorNames, "SetCustomField")
 
 	return p
 }
 
+// Admin
+// ID returns the rule's stable identifier, used in the rulesweb API paths.
+func (p *periodic) ID() string {
+	return p.id
+}
+
+// Workspace returns the Asana workspace name this rule operates in.
+func (p *periodic) Workspace() string {
+	return p.workspace
+}
+
+// Name returns a short human-readable identifier for the rule.
+func (p *periodic) Name() string {
+	return fmt.Sprintf("%s (%s)", p.workspace, p.id)
+}
+
+// Describe summarizes the rule's configured gates, mutators, filters and
+// actors, for display in admin tooling.
+func (p *periodic) Describe() string {
+	return fmt.Sprintf(
+		"workspace=%s gates=%d queryMutators=%d taskFilters=%d taskActors=%d paused=%t",
+		p.workspace, len(p.gates), len(p.queryMutators), len(p.taskFilters), len(p.taskActors), p.Paused(),
+	)
+}
+
+// Paused reports whether the rule's loop is currently skipping exec.
+func (p *periodic) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.paused
+}
+
+// SetPaused pauses or resumes the rule's loop; checked at the top of each
+// iteration.
+func (p *periodic) SetPaused(paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused = paused
+}
+
+// LastRun returns the most recent exec's record, or nil if it hasn't run
+// yet.
+func (p *periodic) LastRun() *RunRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.history) == 0 {
+		return nil
+	}
+
+	return p.history[len(p.history)-1]
+}
+
+// History returns up to the last ruleHistorySize exec records, oldest
+// first.
+func (p *periodic) History() []*RunRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ret := make([]*RunRecord, len(p.history))
+	copy(ret, p.history)
+	return ret
+}
+
+func (p *periodic) recordRun(rec *RunRecord) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.history = append(p.history, rec)
+	if len(p.history) > ruleHistorySize {
+		p.history = p.history[len(p.history)-ruleHistorySize:]
+	}
+}
+
+func (p *periodic) logPhaseError(phase, taskGID string, err error) {
+	args := []any{"rule_id", p.id, "workspace", p.workspace, "phase", phase, "error", err}
+	if taskGID != "" {
+		args = append(args, "task_gid", taskGID)
+	}
+
+	logger.Error("rule phase failed", args...)
+}
+
+// RunNow executes the rule's query/filter/actor pipeline immediately,
+// bypassing gates, for manual triggering from admin tooling. It returns an
+// error if called before Loop has started the rule.
+func (p *periodic) RunNow(ctx context.Context) error {
+	if p.client == nil {
+		return fmt.Errorf("rule %s: not started yet", p.id)
+	}
+
+	return p.exec(ctx, p.client, true)
+}
+
 // Infra
-func (p *periodic) start(client *client.Client) {
+func (p *periodic) start(ctx context.Context, client *client.Client) {
+	p.client = client
+
 	err := p.validate()
 	if err != nil {
 		panic(err)
 	}
 
-	go p.loop(client)
+	go p.loop(ctx, client)
 }
 
 func (p *periodic) validate() error {
-	return nil
+	return p.cronErr
 }
 
 func (p *periodic) wait() {
 	<-p.done
 }
 
-func (p *periodic) loop(client *client.Client) {
+func (p *periodic) loop(ctx context.Context, client *client.Client) {
+	defer close(p.done)
+
+	last := time.Now()
+
 	for {
-		err := p.exec(client)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if p.Paused() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		var scheduled time.Time
+		switch {
+		case p.cron != nil:
+			scheduled = p.cron.nextFire(last)
+		case p.interval > 0:
+			scheduled = last.Add(p.interval)
+		default:
+			scheduled = last
+		}
+
+		fire := scheduled
+		if p.jitter > 0 {
+			fire = fire.Add(time.Duration(rand.Int63n(int64(p.jitter))))
+		}
+
+		if sleep := time.Until(fire); sleep > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(sleep):
+			}
+		}
+
+		execCtx := ctx
+		var cancel context.CancelFunc
+		if p.timeout > 0 {
+			execCtx, cancel = context.WithTimeout(ctx, p.timeout)
+		}
+
+		err := p.exec(execCtx, client, false)
+		if cancel != nil {
+			cancel()
+		}
 		if err != nil {
-			fmt.Printf("ERROR: %s\n", err)
+			logger.Error("rule exec failed",
+				"rule_id", p.id,
+				"workspace", p.workspace,
+				"error", err,
+			)
 			// continue
 		}
-	}
 
-	close(p.done)
+		var next time.Time
+		switch {
+		case p.cron != nil:
+			next = p.cron.nextFire(scheduled)
+		case p.interval > 0:
+			next = scheduled.Add(p.interval)
+		default:
+			next = scheduled
+		}
+
+		if now := time.Now(); now.After(next) {
+			// exec ran past the next scheduled fire; coalesce any fires we
+			// missed instead of queuing them up.
+			last = now
+		} else {
+			last = scheduled
+		}
+	}
 }
 
-func (p *periodic) exec(c *client.Client) error {
-	wc, err := p.workspaceClientGetter(c)
+func (p *periodic) exec(ctx context.Context, c *client.Client, skipGates bool) (err error) {
+	rec := &RunRecord{At: time.Now()}
+	defer func() {
+		if err != nil {
+			rec.Error = err.Error()
+			ruleExecutionsTotal.WithLabelValues(p.id, "error").Inc()
+		} else {
+			ruleExecutionsTotal.WithLabelValues(p.id, "ok").Inc()
+		}
+		p.recordRun(rec)
+	}()
+
+	wc, err := p.workspaceClientGetter(ctx, c)
 	if err != nil {
+		asanaAPIErrorsTotal.WithLabelValues("workspace").Inc()
 		return err
 	}
 
-	for _, g := range p.gates {
-		ok, err := g(wc)
-		if err != nil {
-			return err
-		}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		if !ok {
-			return nil
+	if !skipGates {
+		start := time.Now()
+		for _, g := range p.gates {
+			if err := ctx.Err(); err != nil {
+				ruleDurationSeconds.WithLabelValues(p.id, "gate").Observe(time.Since(start).Seconds())
+				return err
+			}
+
+			ok, err := g(ctx, wc)
+			if err != nil {
+				p.logPhaseError("gate", "", err)
+				ruleDurationSeconds.WithLabelValues(p.id, "gate").Observe(time.Since(start).Seconds())
+				return err
+			}
+
+			if !ok {
+				ruleDurationSeconds.WithLabelValues(p.id, "gate").Observe(time.Since(start).Seconds())
+				return nil
+			}
 		}
+		ruleDurationSeconds.WithLabelValues(p.id, "gate").Observe(time.Since(start).Seconds())
 	}
 
 	q := &client.SearchQuery{}
 
+	start := time.Now()
 	for _, mut := range p.queryMutators {
-		err = mut(wc, q)
+		if err := ctx.Err(); err != nil {
+			ruleDurationSeconds.WithLabelValues(p.id, "query").Observe(time.Since(start).Seconds())
+			return err
+		}
+
+		err = mut(ctx, wc, q)
 		if err != nil {
+			p.logPhaseError("query", "", err)
+			ruleDurationSeconds.WithLabelValues(p.id, "query").Observe(time.Since(start).Seconds())
 			return err
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		ruleDurationSeconds.WithLabelValues(p.id, "query").Observe(time.Since(start).Seconds())
+		return err
+	}
+
 	tasks, err := wc.Search(q)
+	ruleDurationSeconds.WithLabelValues(p.id, "query").Observe(time.Since(start).Seconds())
 	if err != nil {
+		asanaAPIErrorsTotal.WithLabelValues("search").Inc()
+		p.logPhaseError("query", "", err)
 		return err
 	}
 
+	rec.Matched = len(tasks)
+	tasksMatchedTotal.WithLabelValues(p.id).Add(float64(len(tasks)))
+
+	start = time.Now()
 	filteredTasks := []*client.Task{}
 	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			ruleDurationSeconds.WithLabelValues(p.id, "filter").Observe(time.Since(start).Seconds())
+			return err
+		}
+
 		included := true
 
 		for _, filter := range p.taskFilters {
-			include, err := filter(wc, q, task)
+			include, err := filter(ctx, wc, q, task)
 			if err != nil {
+				p.logPhaseError("filter", task.GID, err)
+				ruleDurationSeconds.WithLabelValues(p.id, "filter").Observe(time.Since(start).Seconds())
 				return err
 			}
 
@@ -461,20 +1036,155 @@ func (p *periodic) exec(c *client.Client) error {
 			filteredTasks = append(filteredTasks, task)
 		}
 	}
+	ruleDurationSeconds.WithLabelValues(p.id, "filter").Observe(time.Since(start).Seconds())
 
-	for _, task := range filteredTasks {
-		for _, act := range p.taskActors {
-			err = act(wc, task)
-			if err != nil {
-				return err
+	rec.Filtered = len(filteredTasks)
+
+	if len(filteredTasks) == 0 {
+		return nil
+	}
+
+	concurrency := p.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if concurrency > len(filteredTasks) {
+		concurrency = len(filteredTasks)
+	}
+
+	taskCh := make(chan *client.Task)
+	errCh := make(chan error, len(filteredTasks))
+
+	wg := sync.WaitGroup{}
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				var task *client.Task
+
+				select {
+				case <-ctx.Done():
+					return
+				case t, ok := <-taskCh:
+					if !ok {
+						return
+					}
+					task = t
+				}
+
+				for i, act := range p.taskActors {
+					name := p.taskActorNames[i]
+
+					actorStart := time.Now()
+					err := act(ctx, wc, task)
+					ruleDurationSeconds.WithLabelValues(p.id, "actor").Observe(time.Since(actorStart).Seconds())
+
+					if err != nil {
+						p.logPhaseError("actor", task.GID, err)
+						errCh <- err
+						break
+					}
+
+					tasksActedTotal.WithLabelValues(p.id, name).Inc()
+				}
 			}
+		}()
+	}
+
+dispatch:
+	for _, task := range filteredTasks {
+		select {
+		case taskCh <- task:
+		case <-ctx.Done():
+			break dispatch
 		}
 	}
+	close(taskCh)
+
+	wg.Wait()
+	close(errCh)
+
+	errs := []error{}
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	failed := len(errs)
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("exec: %d of %d tasks failed: %w", failed, len(filteredTasks), errors.Join(errs...))
+	}
 
 	return nil
 }
 
 // Helpers
+func resolveCustomField(wc *client.WorkspaceClient, name string) (*client.CustomField, error) {
+	fieldsByName, err := wc.GetWorkspaceCustomFieldsByName()
+	if err != nil {
+		return nil, err
+	}
+
+	cf, found := fieldsByName[name]
+	if !found {
+		return nil, fmt.Errorf("Custom field '%s' not found", name)
+	}
+
+	return cf, nil
+}
+
+// coerceCustomFieldValue resolves an enum/multi-enum option name to its
+// GID and coerces numeric values to float64 (GetTaskCustomFieldValue
+// always returns a number field's value as float64), leaving text values
+// untouched.
+func coerceCustomFieldValue(cf *client.CustomField, value any) (any, error) {
+	if cf.Type == "enum" || cf.Type == "multi_enum" {
+		s, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+
+		return cf.OptionGID(s)
+	}
+
+	if cf.Type == "number" {
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int32:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float32:
+			return float64(v), nil
+		}
+	}
+
+	return value, nil
+}
+
+// customFieldOptionGIDs resolves each of names against cf's enum/multi-enum
+// options, in order.
+func customFieldOptionGIDs(cf *client.CustomField, names []string) ([]string, error) {
+	gids := make([]string, 0, len(names))
+
+	for _, name := range names {
+		gid, err := cf.OptionGID(name)
+		if err != nil {
+			return nil, err
+		}
+
+		gids = append(gids, gid)
+	}
+
+	return gids, nil
+}
+
 func fixUnlinkedURL(node *html.Node) {
 	if node == nil {
 		return
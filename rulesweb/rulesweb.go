@@ -0,0 +1,19 @@
+// Package rulesweb is a thin alias for the rules package's built-in admin
+// HTTP surface (see rules.AdminHandler and rules.ServeAdmin), kept for
+// callers that mount it as a standalone subpackage.
+package rulesweb
+
+import "net/http"
+
+import "github.com/firestuff/automana/rules"
+
+// Handler returns the admin HTTP handler; see rules.AdminHandler.
+func Handler() http.Handler {
+	return rules.AdminHandler()
+}
+
+// ServeAdmin starts the admin HTTP handler listening on addr in the
+// background. Call it before rules.Loop(). See rules.ServeAdmin.
+func ServeAdmin(addr string) error {
+	return rules.ServeAdmin(addr)
+}